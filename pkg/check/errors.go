@@ -0,0 +1,43 @@
+package check
+
+// Reason classifies why a check failed. It is reported as a metric label,
+// so it must stay a small, fixed set of values rather than free-form error
+// text.
+type Reason string
+
+const (
+	// ReasonAuthFailed means the check could not authenticate against vCenter.
+	ReasonAuthFailed Reason = "AuthFailed"
+	// ReasonConfigInvalid means the cloud provider / infrastructure config
+	// could not be parsed or was missing required fields.
+	ReasonConfigInvalid Reason = "ConfigInvalid"
+	// ReasonClusterMisconfigured means vCenter was reachable but the cluster
+	// itself is set up in a way the check cannot tolerate (e.g. missing
+	// permissions, unsupported topology).
+	ReasonClusterMisconfigured Reason = "ClusterMisconfigured"
+	// ReasonTimeout means the check did not complete within its deadline.
+	ReasonTimeout Reason = "Timeout"
+	// ReasonTransient means the check failed in a way that is expected to
+	// resolve on its own on the next run (e.g. a dropped connection).
+	ReasonTransient Reason = "Transient"
+)
+
+// CheckError wraps an error with the Reason that should be reported in
+// metrics and status conditions, instead of letting the raw error string
+// leak into metric labels.
+type CheckError struct {
+	Reason Reason
+	Err    error
+}
+
+func NewCheckError(reason Reason, err error) error {
+	return &CheckError{Reason: reason, Err: err}
+}
+
+func (e *CheckError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CheckError) Unwrap() error {
+	return e.Err
+}
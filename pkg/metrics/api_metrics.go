@@ -0,0 +1,141 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/vmware/govmomi/soap"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+const (
+	MethodLabel       = "method"
+	ResourceKindLabel = "resource_kind"
+	ResultLabel       = "result"
+)
+
+// Values reported under ResultLabel. Kept small and stable so the metric
+// stays usable as an SLI instead of growing one series per error string.
+const (
+	ResultSuccess          = "success"
+	ResultNotFound         = "not_found"
+	ResultPermissionDenied = "permission_denied"
+	ResultTimeout          = "timeout"
+	ResultError            = "error"
+)
+
+// Resource kinds passed to RecordAPICall / NewMeasuredClient.
+const (
+	KindHost      = "host"
+	KindVM        = "vm"
+	KindDatastore = "datastore"
+	KindCluster   = "cluster"
+)
+
+var (
+	apiRequestDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Name:           "vsphere_api_request_duration_seconds",
+			Help:           "Duration in seconds of individual vCenter API calls made by vsphere-problem-detector, by method, resource kind and result.",
+			Buckets:        metrics.ExponentialBuckets(0.01, 2, 15),
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{MethodLabel, ResourceKindLabel, ResultLabel},
+	)
+
+	apiRequestTotal = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Name:           "vsphere_api_request_total",
+			Help:           "Number of vCenter API calls made by vsphere-problem-detector, by method, resource kind and result.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{MethodLabel, ResourceKindLabel, ResultLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(apiRequestDuration, apiRequestTotal)
+}
+
+// RecordAPICall records the outcome of a single vCenter API call. It is safe
+// to call from multiple goroutines.
+func (c *Collector) RecordAPICall(method, kind string, err error, d time.Duration) {
+	result := classifyAPIError(err)
+	apiRequestDuration.WithLabelValues(method, kind, result).Observe(d.Seconds())
+	apiRequestTotal.WithLabelValues(method, kind, result).Inc()
+}
+
+// classifyAPIError maps a govmomi/vim25 error into one of the stable
+// ResultLabel values.
+func classifyAPIError(err error) string {
+	if err == nil {
+		return ResultSuccess
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ResultTimeout
+	}
+
+	if soap.IsSoapFault(err) {
+		switch soap.ToSoapFault(err).VimFault().(type) {
+		case types.NotAuthenticated, types.NoPermission, types.InvalidLogin:
+			return ResultPermissionDenied
+		}
+	}
+	if soap.IsVimFault(err) {
+		switch soap.ToVimFault(err).(type) {
+		case *types.NotAuthenticated, *types.NoPermission, *types.InvalidLogin:
+			return ResultPermissionDenied
+		case *types.ManagedObjectNotFound:
+			return ResultNotFound
+		}
+	}
+
+	return ResultError
+}
+
+// MeasuredClient wraps a vim25.Client's soap.RoundTripper so that every SOAP
+// call issued through the client is timed and recorded via RecordAPICall.
+// Checks should install it once, right after logging in:
+//
+//	client.RoundTripper = metrics.NewMeasuredClient(client.RoundTripper, metrics.KindHost, collector)
+type MeasuredClient struct {
+	soap.RoundTripper
+
+	collector *Collector
+	kind      string
+}
+
+// NewMeasuredClient returns a soap.RoundTripper that delegates to next and
+// records every call against collector under the given resource kind.
+func NewMeasuredClient(next soap.RoundTripper, kind string, collector *Collector) *MeasuredClient {
+	return &MeasuredClient{
+		RoundTripper: next,
+		collector:    collector,
+		kind:         kind,
+	}
+}
+
+func (m *MeasuredClient) RoundTrip(ctx context.Context, req, res soap.HasFault) error {
+	start := time.Now()
+	err := m.RoundTripper.RoundTrip(ctx, req, res)
+	m.collector.RecordAPICall(soapMethodName(req), m.kind, err, time.Since(start))
+	return err
+}
+
+// soapMethodName recovers the vim25 method name (e.g. "RetrievePropertiesEx",
+// "QueryPerf") from the generated request type, so callers don't have to pass
+// it in by hand at every call site.
+func soapMethodName(req soap.HasFault) string {
+	t := reflect.TypeOf(req)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	// Generated request types are named "<Method>Body" (e.g.
+	// "RetrievePropertiesExBody"), not the method name itself.
+	return strings.TrimSuffix(t.Name(), "Body")
+}
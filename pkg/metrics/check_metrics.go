@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+
+	"github.com/openshift/vsphere-problem-detector/pkg/check"
+)
+
+const CheckLabel = "check"
+
+const ReasonLabel = "reason"
+
+var (
+	checkDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Name:           "vsphere_problem_detector_check_duration_seconds",
+			Help:           "Time it took to execute a given check, in seconds.",
+			Buckets:        metrics.ExponentialBuckets(0.1, 2, 10),
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{CheckLabel},
+	)
+
+	checkErrorsTotal = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Name:           "vsphere_problem_detector_check_errors_total",
+			Help:           "Number of times a given check has failed, by reason.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{CheckLabel, ReasonLabel},
+	)
+
+	checkLastSuccessTimestamp = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_problem_detector_check_last_success_timestamp_seconds",
+			Help:           "Time, in unix seconds, a given check last completed successfully.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{CheckLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(checkDuration, checkErrorsTotal, checkLastSuccessTimestamp)
+}
+
+// CheckSpan tracks a single run of a single check, started by
+// Collector.StartCheck and ended by Finish.
+type CheckSpan struct {
+	collector *Collector
+	checkName string
+	start     time.Time
+}
+
+// StartCheck begins timing a run of the named check. Call Finish on the
+// returned CheckSpan when the check completes, regardless of outcome.
+func (c *Collector) StartCheck(name string) *CheckSpan {
+	return &CheckSpan{
+		collector: c,
+		checkName: name,
+		start:     time.Now(),
+	}
+}
+
+// Finish records the duration of the check run and, if err is non-nil,
+// classifies and counts it as a failure. err should be (or wrap) a
+// *check.CheckError so the failure is attributed a stable reason; anything
+// else is reported under the "Unknown" reason.
+func (s *CheckSpan) Finish(err error) {
+	checkDuration.WithLabelValues(s.checkName).Observe(time.Since(s.start).Seconds())
+
+	if err != nil {
+		checkErrorsTotal.WithLabelValues(s.checkName, reasonForError(err)).Inc()
+		return
+	}
+
+	checkLastSuccessTimestamp.WithLabelValues(s.checkName).Set(float64(time.Now().Unix()))
+}
+
+func reasonForError(err error) string {
+	var checkErr *check.CheckError
+	if errors.As(err, &checkErr) {
+		return string(checkErr.Reason)
+	}
+	return "Unknown"
+}
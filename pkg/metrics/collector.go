@@ -4,15 +4,30 @@ import (
 	"sync"
 
 	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
 )
 
 type Collector struct {
 	metrics.BaseStableCollector
 	lock sync.RWMutex
 
-	storedMetrics    []metrics.Metric
-	staleMetrics     []metrics.Metric
-	markStaleMetrics bool
+	// storedMetrics and staleMetrics are keyed first by vCenter UUID and
+	// then by check name, so that a failing check or an unreachable
+	// vCenter only affects its own gauges rather than every check's
+	// metrics, or every vCenter's metrics, at once.
+	storedMetrics map[string]map[string][]metrics.Metric
+	staleMetrics  map[string]map[string][]metrics.Metric
+	// markStaleMetrics records, per vCenter and per check, whether
+	// CollectWithStability should serve staleMetrics instead of
+	// storedMetrics for that pair.
+	markStaleMetrics map[string]map[string]bool
+
+	// vCenterInfoLabels remembers the label tuple last published on
+	// vCenterInfo for each vCenter UUID, so a later RegisterVCenter or
+	// UpdateVCenterVersion call can delete it before publishing a new one.
+	// An info metric must stay a single series per entity, or a
+	// group_left join against it becomes ambiguous.
+	vCenterInfoLabels map[string][5]string
 }
 
 var _ metrics.StableCollector = &Collector{}
@@ -22,6 +37,12 @@ const (
 	BuildLabel      = "build"
 	ApiVersionLabel = "api_version"
 	vCenterUUID     = "uuid"
+	// VCenterLabel identifies which vCenter a metric was collected from.
+	// vsphere_vcenter_info does not carry this label; check metrics join
+	// against it on vCenterUUID, which both carry.
+	VCenterLabel = "vcenter"
+	// HostLabel carries a vCenter's hostname on vsphere_vcenter_info.
+	HostLabel = "host"
 
 	HwVersionLabel   = "hw_version"
 	cbtMismatchLabel = "cbt"
@@ -33,29 +54,43 @@ var (
 	EsxiVersionMetric = metrics.NewDesc(
 		"vsphere_esxi_version_total",
 		"Number of ESXi hosts with given version.",
-		[]string{VersionLabel, ApiVersionLabel}, nil,
+		[]string{VersionLabel, ApiVersionLabel, VCenterLabel, vCenterUUID}, nil,
 		metrics.ALPHA, "",
 	)
 	HwVersionMetric = metrics.NewDesc(
 		"vsphere_node_hw_version_total",
 		"Number of vSphere nodes with given HW version.",
-		[]string{HwVersionLabel}, nil,
+		[]string{HwVersionLabel, VCenterLabel, vCenterUUID}, nil,
 		metrics.ALPHA, "",
 	)
 
 	CbtMismatchMetric = metrics.NewDesc(
 		"vsphere_vm_cbt_checks",
 		"Boolean metric based on whether ctkEnabled is consistent or not across all nodes in the cluster.",
-		[]string{cbtMismatchLabel}, nil,
+		[]string{cbtMismatchLabel, VCenterLabel, vCenterUUID}, nil,
 		metrics.ALPHA, "",
 	)
+
+	vCenterInfo = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_vcenter_info",
+			Help:           "Info metric, always 1, labelled with the identity and version of a vCenter this cluster talks to.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterUUID, HostLabel, VersionLabel, BuildLabel, ApiVersionLabel},
+	)
 )
 
+func init() {
+	legacyregistry.MustRegister(vCenterInfo)
+}
+
 func NewMetricsCollector() *Collector {
 	return &Collector{
-		storedMetrics:    []metrics.Metric{},
-		staleMetrics:     []metrics.Metric{},
-		markStaleMetrics: false,
+		storedMetrics:     map[string]map[string][]metrics.Metric{},
+		staleMetrics:      map[string]map[string][]metrics.Metric{},
+		markStaleMetrics:  map[string]map[string]bool{},
+		vCenterInfoLabels: map[string][5]string{},
 	}
 }
 
@@ -69,43 +104,114 @@ func (c *Collector) CollectWithStability(ch chan<- metrics.Metric) {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
 
-	if c.markStaleMetrics {
-		for _, m := range c.staleMetrics {
-			ch <- m
+	for vcenter, checks := range c.storedMetrics {
+		for check, stored := range checks {
+			if c.markStaleMetrics[vcenter][check] {
+				continue
+			}
+			for _, m := range stored {
+				ch <- m
+			}
 		}
-	} else {
-		for _, m := range c.storedMetrics {
-			ch <- m
+	}
+	for vcenter, checks := range c.staleMetrics {
+		for check, stale := range checks {
+			if !c.markStaleMetrics[vcenter][check] {
+				continue
+			}
+			for _, m := range stale {
+				ch <- m
+			}
 		}
 	}
 }
 
-func (c *Collector) AddMetric(m metrics.Metric) {
+// RegisterVCenter records that uuid/host is a vCenter this cluster talks to
+// and publishes it via vsphere_vcenter_info, so dashboards have something
+// stable to join check metrics against even before any check has run.
+// Version details can be filled in later, once a check has actually logged
+// in and read them, via UpdateVCenterVersion.
+func (c *Collector) RegisterVCenter(uuid, host string) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	c.storedMetrics = append(c.storedMetrics, m)
+	c.initVCenterLocked(uuid)
+	c.setVCenterInfoLocked(uuid, host, "", "", "")
 }
 
-func (c *Collector) ClearStoredMetric() {
+// UpdateVCenterVersion fills in the version, build and API version labels
+// of the vsphere_vcenter_info metric for a previously registered vCenter.
+func (c *Collector) UpdateVCenterVersion(uuid, host, version, build, apiVersion string) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	// if last check did not finish, we should keep reporting stale metrics rather than risk
-	// clearing them out
-	if !c.markStaleMetrics {
-		c.markStaleMetrics = true
-		c.staleMetrics = c.storedMetrics
+	c.setVCenterInfoLocked(uuid, host, version, build, apiVersion)
+}
+
+// setVCenterInfoLocked publishes a fresh vsphere_vcenter_info series for
+// uuid and deletes whatever series was previously published for it. Without
+// this, RegisterVCenter's placeholder (empty version/build/api_version) and
+// a later UpdateVCenterVersion call would each leave their own series
+// behind, so the info metric would carry two rows per vCenter instead of
+// one. c.lock must be held by the caller.
+func (c *Collector) setVCenterInfoLocked(uuid, host, version, build, apiVersion string) {
+	if prev, ok := c.vCenterInfoLabels[uuid]; ok {
+		vCenterInfo.DeleteLabelValues(prev[0], prev[1], prev[2], prev[3], prev[4])
+	}
+	vCenterInfo.WithLabelValues(uuid, host, version, build, apiVersion).Set(1)
+	c.vCenterInfoLabels[uuid] = [5]string{uuid, host, version, build, apiVersion}
+}
+
+func (c *Collector) initVCenterLocked(vcenter string) {
+	if _, ok := c.storedMetrics[vcenter]; !ok {
+		c.storedMetrics[vcenter] = map[string][]metrics.Metric{}
+		c.staleMetrics[vcenter] = map[string][]metrics.Metric{}
+		c.markStaleMetrics[vcenter] = map[string]bool{}
 	}
-	c.storedMetrics = []metrics.Metric{}
 }
 
-// FinishedAllChecks updates staleMetrics with storedMetrics so as
-// both slices point to same values
+// AddMetric records a metric produced by the named check against the given
+// vCenter.
+func (c *Collector) AddMetric(vcenter, check string, m metrics.Metric) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.initVCenterLocked(vcenter)
+	c.storedMetrics[vcenter][check] = append(c.storedMetrics[vcenter][check], m)
+}
+
+// ClearStoredMetric prepares the named check to be re-run against the given
+// vCenter: its previously stored metrics become the stale fallback (served
+// until FinishedAllChecks promotes a fresh set) and its stored slice is
+// reset. Every other (vCenter, check) pair -- including other checks
+// against this same vCenter, and this same check against other vCenters --
+// is left untouched, so a single failing vCenter can no longer wipe out
+// metrics collected from the rest.
+func (c *Collector) ClearStoredMetric(vcenter, check string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.initVCenterLocked(vcenter)
+	// if the last run of this check did not finish, we should keep reporting
+	// its stale metrics rather than risk clearing them out
+	if !c.markStaleMetrics[vcenter][check] {
+		c.markStaleMetrics[vcenter][check] = true
+		c.staleMetrics[vcenter][check] = c.storedMetrics[vcenter][check]
+	}
+	c.storedMetrics[vcenter][check] = nil
+}
+
+// FinishedAllChecks promotes every (vCenter, check) pair's freshly stored
+// metrics to be its new stale fallback and clears its stale flag,
+// independently per pair.
 func (c *Collector) FinishedAllChecks() {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	c.staleMetrics = c.storedMetrics
-	c.markStaleMetrics = false
+	for vcenter, checks := range c.storedMetrics {
+		for check, stored := range checks {
+			c.staleMetrics[vcenter][check] = stored
+			c.markStaleMetrics[vcenter][check] = false
+		}
+	}
 }
@@ -0,0 +1,237 @@
+// Package perf periodically pulls vSphere PerformanceManager counters for
+// hosts, VMs and datastores and republishes them as Prometheus metrics.
+//
+// vCenter keeps a 20-second real-time interval for hosts and VMs for only a
+// short rolling window, and coarser 5/30/2h rollups beyond that; querying it
+// naively either misses samples or re-fetches ones already seen. Collector
+// uses a TSCache to remember the last sample timestamp emitted per
+// (moref, counter, instance) series and always starts the next QueryPerf at
+// max(now-lookback*interval, cachedHWM), advancing the cache only for
+// samples it actually emits.
+package perf
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vmware/govmomi/performance"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+const (
+	// RealtimeInterval is vCenter's fixed real-time sampling interval for
+	// hosts and VMs.
+	RealtimeInterval = 20 * time.Second
+	// DatastoreInterval is the shortest historical rollup interval (5
+	// minutes); datastores have no real-time interval.
+	DatastoreInterval = 300 * time.Second
+	// lookbackSamples bounds how far behind now a QueryPerf call is allowed
+	// to look, in sampling intervals, to tolerate vCenter clock skew and an
+	// occasional missed collection cycle.
+	lookbackSamples = 3
+	// DefaultMaxQueryMetrics caps how many (moref, counter) pairs, one
+	// PerfQuerySpec each, are batched into a single QueryPerf call,
+	// matching vCenter's default per-call query-size limit.
+	DefaultMaxQueryMetrics = 256
+)
+
+const EntityLabel = "name"
+
+var (
+	hostCPUUsage = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_host_cpu_usage_average",
+			Help:           "Average CPU usage of an ESXi host, in percent, as reported by vCenter's PerformanceManager.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{EntityLabel},
+	)
+	vmDiskMaxTotalLatency = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_vm_disk_maxTotalLatency",
+			Help:           "Highest latency, in milliseconds, observed across a VM's disks during the collection interval.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{EntityLabel},
+	)
+	datastoreIops = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_datastore_datastoreIops",
+			Help:           "Aggregated IOPS observed for a datastore during the collection interval.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{EntityLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(hostCPUUsage, vmDiskMaxTotalLatency, datastoreIops)
+}
+
+// counterSpec binds one vSphere performance counter to the gauge it is
+// republished as.
+type counterSpec struct {
+	group    string
+	name     string
+	rollup   types.PerfSummaryType
+	interval time.Duration
+	gauge    *metrics.GaugeVec
+}
+
+var counterSpecs = []counterSpec{
+	{group: "cpu", name: "usage", rollup: types.PerfSummaryTypeAverage, interval: RealtimeInterval, gauge: hostCPUUsage},
+	{group: "disk", name: "maxTotalLatency", rollup: types.PerfSummaryTypeLatest, interval: RealtimeInterval, gauge: vmDiskMaxTotalLatency},
+	{group: "datastore", name: "datastoreIops", rollup: types.PerfSummaryTypeAverage, interval: DatastoreInterval, gauge: datastoreIops},
+}
+
+// Collector periodically pulls vSphere PerformanceManager counters for
+// hosts, VMs and datastores and republishes them as Prometheus metrics. It
+// is registered alongside, and collected independently of, metrics.Collector:
+// unlike the per-check gauges there, these are ordinary GaugeVecs that don't
+// need stale/fresh bookkeeping -- a missed sample simply isn't observed.
+type Collector struct {
+	manager         *performance.Manager
+	cache           *TSCache
+	maxQueryMetrics int
+	workers         int
+}
+
+// NewCollector builds a Collector that queries the PerformanceManager
+// through client. maxQueryMetrics caps how many (moref, counter) pairs go
+// into a single QueryPerf batch, so a large cluster's full inventory can be
+// collected without exceeding vCenter's per-call query-size limit; 0
+// selects DefaultMaxQueryMetrics. workers bounds how many of those batches
+// are queried concurrently.
+func NewCollector(client *vim25.Client, maxQueryMetrics, workers int) *Collector {
+	if maxQueryMetrics <= 0 {
+		maxQueryMetrics = DefaultMaxQueryMetrics
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+	return &Collector{
+		manager:         performance.NewManager(client),
+		cache:           NewTSCache(),
+		maxQueryMetrics: maxQueryMetrics,
+		workers:         workers,
+	}
+}
+
+// CollectHosts queries the cpu.usage.average counter for every host in refs
+// and updates vsphere_host_cpu_usage_average.
+func (c *Collector) CollectHosts(ctx context.Context, refs []types.ManagedObjectReference, names map[types.ManagedObjectReference]string) error {
+	return c.collect(ctx, refs, names, counterSpecs[0])
+}
+
+// CollectVMs queries the disk.maxTotalLatency.latest counter for every VM in
+// refs and updates vsphere_vm_disk_maxTotalLatency.
+func (c *Collector) CollectVMs(ctx context.Context, refs []types.ManagedObjectReference, names map[types.ManagedObjectReference]string) error {
+	return c.collect(ctx, refs, names, counterSpecs[1])
+}
+
+// CollectDatastores queries the datastore.datastoreIops.average counter for
+// every datastore in refs and updates vsphere_datastore_datastoreIops.
+func (c *Collector) CollectDatastores(ctx context.Context, refs []types.ManagedObjectReference, names map[types.ManagedObjectReference]string) error {
+	return c.collect(ctx, refs, names, counterSpecs[2])
+}
+
+// collect batches refs into shards of at most maxQueryMetrics morefs,
+// fetches counterInfo once, queries each shard concurrently (bounded by
+// workers), and publishes the latest sample returned for each moref,
+// advancing the TSCache only for the (moref, instance) series actually
+// emitted. Earlier samples in the same result are not published.
+func (c *Collector) collect(ctx context.Context, refs []types.ManagedObjectReference, names map[types.ManagedObjectReference]string, spec counterSpec) error {
+	if len(refs) == 0 {
+		return nil
+	}
+
+	counterInfo, err := c.manager.CounterInfoByName(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch perf counter metadata: %w", err)
+	}
+	fullName := spec.group + "." + spec.name + "." + string(spec.rollup)
+	info, ok := counterInfo[fullName]
+	if !ok {
+		return fmt.Errorf("unknown perf counter %q", fullName)
+	}
+
+	shards := shardRefs(refs, c.maxQueryMetrics)
+	errCh := make(chan error, len(shards))
+	sem := make(chan struct{}, c.workers)
+
+	for _, shard := range shards {
+		shard := shard
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem }()
+			errCh <- c.collectShard(ctx, shard, names, spec, info)
+		}()
+	}
+	for range shards {
+		if err := <-errCh; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Collector) collectShard(ctx context.Context, refs []types.ManagedObjectReference, names map[types.ManagedObjectReference]string, spec counterSpec, info *types.PerfCounterInfo) error {
+	now := time.Now()
+
+	specs := make([]types.PerfQuerySpec, 0, len(refs))
+	for _, ref := range refs {
+		start := c.cache.StartTime(ref, info.Key, "", now, spec.interval, lookbackSamples)
+		specs = append(specs, types.PerfQuerySpec{
+			Entity:     ref,
+			MetricId:   []types.PerfMetricId{{CounterId: info.Key, Instance: ""}},
+			IntervalId: int32(spec.interval.Seconds()),
+			StartTime:  types.NewTime(start),
+			EndTime:    types.NewTime(now),
+		})
+	}
+
+	results, err := c.manager.Query(ctx, specs)
+	if err != nil {
+		return fmt.Errorf("failed to query perf counter %s.%s: %w", spec.group, spec.name, err)
+	}
+
+	for _, result := range results {
+		metricResult, ok := result.(*types.PerfEntityMetric)
+		if !ok || len(metricResult.Value) == 0 {
+			continue
+		}
+		name := names[metricResult.Entity]
+		if name == "" {
+			name = metricResult.Entity.Value
+		}
+
+		series, ok := metricResult.Value[0].(*types.PerfMetricIntSeries)
+		if !ok || len(series.Value) == 0 || len(series.Value) != len(metricResult.SampleInfo) {
+			continue
+		}
+		last := len(series.Value) - 1
+		sampleTime := metricResult.SampleInfo[last].Timestamp
+
+		spec.gauge.WithLabelValues(name).Set(float64(series.Value[last]))
+		c.cache.Advance(metricResult.Entity, info.Key, "", sampleTime)
+	}
+	return nil
+}
+
+// shardRefs splits refs into chunks of at most maxQueryMetrics entries each.
+func shardRefs(refs []types.ManagedObjectReference, maxQueryMetrics int) [][]types.ManagedObjectReference {
+	var shards [][]types.ManagedObjectReference
+	for len(refs) > 0 {
+		n := maxQueryMetrics
+		if n > len(refs) {
+			n = len(refs)
+		}
+		shards = append(shards, refs[:n])
+		refs = refs[n:]
+	}
+	return shards
+}
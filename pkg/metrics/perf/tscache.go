@@ -0,0 +1,64 @@
+package perf
+
+import (
+	"sync"
+	"time"
+
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// sampleKey identifies a single performance counter series: one moref, one
+// counter, and (for per-device counters such as per-disk latency) one
+// instance. An empty instance means the counter is aggregated across the
+// whole entity.
+type sampleKey struct {
+	moref     types.ManagedObjectReference
+	counterID int32
+	instance  string
+}
+
+// TSCache remembers the timestamp of the last sample successfully emitted
+// for each (moref, counter, instance) series, so repeated QueryPerf calls
+// only request samples we haven't already collected.
+type TSCache struct {
+	lock sync.Mutex
+	hwm  map[sampleKey]time.Time
+}
+
+func NewTSCache() *TSCache {
+	return &TSCache{
+		hwm: map[sampleKey]time.Time{},
+	}
+}
+
+// StartTime returns the time a QueryPerf call should start at for the given
+// series: the later of the cached high-water-mark and now minus
+// lookback*interval. The lookback floor bounds how far back a query reaches
+// after a restart or a long pause; samples older than the floor are not
+// requested.
+func (c *TSCache) StartTime(moref types.ManagedObjectReference, counterID int32, instance string, now time.Time, interval time.Duration, lookback int) time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	floor := now.Add(-time.Duration(lookback) * interval)
+	hwm, ok := c.hwm[sampleKey{moref, counterID, instance}]
+	if !ok || hwm.Before(floor) {
+		return floor
+	}
+	return hwm
+}
+
+// Advance records that a sample timestamped ts was emitted for the given
+// series, so future StartTime calls won't re-request it. The mark only
+// moves forward: a ts at or before the cached one is ignored. Only the
+// caller that actually emitted the sample should advance the cache -- a
+// failed or partial query must not advance it.
+func (c *TSCache) Advance(moref types.ManagedObjectReference, counterID int32, instance string, ts time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	key := sampleKey{moref, counterID, instance}
+	if ts.After(c.hwm[key]) {
+		c.hwm[key] = ts
+	}
+}
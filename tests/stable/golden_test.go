@@ -0,0 +1,81 @@
+//go:build stable
+
+// Package stable guards the public contract of the metrics this operator
+// exposes. It is excluded from the default `go test ./...` package list
+// (see the build tag above) because it is not a correctness test of any
+// particular check -- it exists to make an accidental rename, help-text
+// edit, or label change to a stable/GA metric fail loudly, in the style of
+// kube-state-metrics' stable-metrics guard. Run it explicitly with
+// `make verify-stable-metrics`.
+//
+// Scope: only the three metrics registered on Collector via
+// DescribeWithStability/CollectWithStability are covered here, although
+// they are themselves declared metrics.ALPHA. The other metrics this
+// package exposes -- vsphere_vcenter_info, the vsphere_api_request_* pair,
+// the vsphere_problem_detector_check_* trio, and the metrics/perf gauges --
+// are also metrics.ALPHA but are registered directly on legacyregistry
+// instead of on Collector; they are expected to keep changing shape as
+// the checks that feed them mature, so pinning them to a golden file this
+// early would just churn the golden on every iteration. Promote a metric
+// into this test's scope when it graduates to a stability level where
+// downstream consumers (OCP monitoring, alertmanager rules) start relying
+// on it.
+package stable
+
+import (
+	"os"
+	"testing"
+
+	k8smetrics "k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/testutil"
+
+	vspheremetrics "github.com/openshift/vsphere-problem-detector/pkg/metrics"
+)
+
+const goldenFile = "testdata/metrics.golden"
+
+// TestStableMetricsContract renders every metric Collector declares via
+// DescribeWithStability/CollectWithStability (see the package doc for why
+// that, and not every metric in this module, is the scope of this test)
+// and compares the result against a checked-in golden file of name, help
+// text, stability level and label set. A diff means the contract changed
+// and the golden file must be updated deliberately, not as a side effect
+// of an unrelated change.
+func TestStableMetricsContract(t *testing.T) {
+	registry := k8smetrics.NewKubeRegistry()
+	collector := vspheremetrics.NewMetricsCollector()
+	if err := registry.CustomMustRegister(collector); err != nil {
+		t.Fatalf("failed to register collector: %v", err)
+	}
+	seedSampleMetrics(collector)
+
+	got, err := testutil.CollectAndFormat(registry)
+	if err != nil {
+		t.Fatalf("failed to collect metrics: %v", err)
+	}
+
+	want, err := os.ReadFile(goldenFile)
+	if err != nil {
+		t.Fatalf("failed to read golden file %s: %v", goldenFile, err)
+	}
+
+	if string(got) != string(want) {
+		t.Errorf("stable metrics contract changed; if intentional, regenerate %s\n--- got ---\n%s\n--- want ---\n%s", goldenFile, got, want)
+	}
+}
+
+// seedSampleMetrics adds one sample per stable metric so its HELP/TYPE and
+// label set actually render: a collector with zero stored metrics emits
+// nothing at all.
+func seedSampleMetrics(c *vspheremetrics.Collector) {
+	c.AddMetric("test-vcenter", "esxi-version", k8smetrics.NewLazyConstMetric(
+		vspheremetrics.EsxiVersionMetric, k8smetrics.GaugeValue, 1,
+		"7.0.3", "7.0.3.0", "test-vcenter-host", "test-vcenter"))
+	c.AddMetric("test-vcenter", "hw-version", k8smetrics.NewLazyConstMetric(
+		vspheremetrics.HwVersionMetric, k8smetrics.GaugeValue, 1,
+		"vmx-15", "test-vcenter-host", "test-vcenter"))
+	c.AddMetric("test-vcenter", "cbt", k8smetrics.NewLazyConstMetric(
+		vspheremetrics.CbtMismatchMetric, k8smetrics.GaugeValue, 0,
+		"false", "test-vcenter-host", "test-vcenter"))
+	c.FinishedAllChecks()
+}